@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package configgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewCmd returns the `configgen` cobra subcommand, for registration with
+// whichever root command the caller's CLI already builds.
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "configgen",
+		Short: "Generate per-environment deployment configs from templates",
+		RunE:  runConfigGen,
+	}
+
+	cmd.Flags().String("env", "dev", "Environment profile to generate, as declared in the manifest")
+	cmd.Flags().String("profile", "", "Path to the manifest file declaring environment profiles (YAML)")
+	cmd.Flags().Bool("dry-run", false, "Render configs and print what would be executed, without applying anything")
+	cmd.Flags().String("output-dir", "", "Directory to write generated configs to")
+	cmd.Flags().String("executor", "skaffold", "Executor to run after generation: \"skaffold\", \"kubectl\", or \"dry-run\"")
+	cmd.Flags().StringSlice("template-root", nil, "Directory to search recursively for .tmpl files (repeatable)")
+	viper.BindPFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runConfigGen(cmd *cobra.Command, args []string) error {
+	env := viper.GetString("env")
+	profilePath := viper.GetString("profile")
+	outputDir := viper.GetString("output-dir")
+	dryRun := viper.GetBool("dry-run")
+	roots := viper.GetStringSlice("template-root")
+
+	if profilePath == "" {
+		return fmt.Errorf("configgen: --profile is required")
+	}
+	if outputDir == "" {
+		return fmt.Errorf("configgen: --output-dir is required")
+	}
+
+	profiles, err := LoadManifest(profilePath)
+	if err != nil {
+		return err
+	}
+
+	executor, err := resolveExecutor(viper.GetString("executor"), dryRun)
+	if err != nil {
+		return err
+	}
+
+	gen := NewGenerator(roots, PbtxtLoader{}, profiles, executor, nil)
+	return gen.Generate(env, outputDir)
+}
+
+func resolveExecutor(name string, dryRun bool) (Executor, error) {
+	if dryRun {
+		return DryRunExecutor{}, nil
+	}
+	switch strings.ToLower(name) {
+	case "skaffold":
+		return SkaffoldExecutor{SubCommand: "run"}, nil
+	case "kubectl":
+		return KubectlExecutor{}, nil
+	case "dry-run":
+		return DryRunExecutor{}, nil
+	default:
+		return nil, fmt.Errorf("configgen: unknown executor %q", name)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package configgen generates per-environment deployment configs (e.g. skaffold
+// YAML) from a set of templates and a manifest of named environment profiles,
+// then optionally hands the generated output to a pluggable Executor. It
+// replaces the old hard-coded dev/staging/prod skaffold_template.go script with
+// a reusable library that supports arbitrary custom environments.
+package configgen
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EnvironmentProfile describes one named deployment target: where its config
+// lives, what its templates should render as, and which executor invocation
+// (if any) should follow generation.
+type EnvironmentProfile struct {
+	// Name is the profile's key in the manifest, e.g. "dev", "staging", "prod".
+	Name string
+	// ConfigFile is the path to the ConfigLoader-readable file describing this
+	// environment (relative to the manifest's directory).
+	ConfigFile string
+	// Deployment is substituted into generated file names, e.g.
+	// "<template>_<Deployment>.yaml".
+	Deployment string
+	// RequireConfirmation gates this profile behind an interactive y/n prompt
+	// before anything is written or executed, e.g. for prod.
+	RequireConfirmation bool
+}
+
+// ConfigLoader parses a profile's config file into the data made available to
+// templates. Implementations exist for pbtxt today; YAML/JSON/HCL can be added
+// without changing Generator.
+type ConfigLoader interface {
+	Load(path string) (interface{}, error)
+}
+
+// Executor hands the generated output off to whatever applies it: `skaffold`,
+// `kubectl apply`, or a dry-run printer.
+type Executor interface {
+	Execute(outputDir string, profile EnvironmentProfile) error
+}
+
+// Generator renders template files found under a set of search roots into an
+// output directory, using a named EnvironmentProfile's config, then invokes an
+// Executor on the result.
+type Generator struct {
+	// SearchRoots are walked recursively for files with a .tmpl extension.
+	SearchRoots []string
+	Loader      ConfigLoader
+	Profiles    map[string]EnvironmentProfile
+	Executor    Executor
+	// FuncMap is merged into every template's function map. See TemplateFuncs
+	// for the default sprig-style helpers.
+	FuncMap template.FuncMap
+}
+
+// NewGenerator constructs a Generator. funcMap may be nil, in which case
+// TemplateFuncs() is used.
+func NewGenerator(roots []string, loader ConfigLoader, profiles map[string]EnvironmentProfile, executor Executor, funcMap template.FuncMap) *Generator {
+	if funcMap == nil {
+		funcMap = TemplateFuncs()
+	}
+	return &Generator{
+		SearchRoots: roots,
+		Loader:      loader,
+		Profiles:    profiles,
+		Executor:    executor,
+		FuncMap:     funcMap,
+	}
+}
+
+// Generate renders every template under g.SearchRoots for the given profile
+// name into outputDir, then invokes g.Executor (a DryRunExecutor can be used to
+// print what would happen instead of applying anything).
+func (g *Generator) Generate(profileName, outputDir string) error {
+	profile, ok := g.Profiles[profileName]
+	if !ok {
+		return fmt.Errorf("configgen: unknown environment profile %q", profileName)
+	}
+
+	if profile.RequireConfirmation {
+		ok, err := confirm(fmt.Sprintf("Are you sure you want to push to %s? [Y/n] ", profile.Name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Infof("not confirmed for %s, exiting", profile.Name)
+			return nil
+		}
+	}
+
+	config, err := g.Loader.Load(profile.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("configgen: failed to load config for profile %q: %w", profileName, err)
+	}
+
+	templateFiles := g.findTemplateFiles()
+	if err := g.renderTemplates(templateFiles, outputDir, profile, config); err != nil {
+		return err
+	}
+
+	if g.Executor == nil {
+		return nil
+	}
+	return g.Executor.Execute(outputDir, profile)
+}
+
+func (g *Generator) findTemplateFiles() []string {
+	var templateFiles []string
+	for _, root := range g.SearchRoots {
+		log.Infof("Searching %s", root)
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && info.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+			if filepath.Ext(p) == ".tmpl" {
+				templateFiles = append(templateFiles, p)
+			}
+			return nil
+		})
+		if err != nil {
+			log.WithError(err).Errorf("error searching %s for templates", root)
+		}
+	}
+	return templateFiles
+}
+
+func (g *Generator) renderTemplates(templateFiles []string, outputDir string, profile EnvironmentProfile, config interface{}) error {
+	for _, templateFile := range templateFiles {
+		ext := path.Ext(templateFile)
+		base := templateFile[:len(templateFile)-len(ext)]
+		outputFile := filepath.Join(outputDir, fmt.Sprintf("%s_%s.yaml", filepath.Base(base), profile.Deployment))
+
+		if err := os.MkdirAll(filepath.Dir(outputFile), os.ModePerm); err != nil {
+			return fmt.Errorf("configgen: could not create output dir for %s: %w", outputFile, err)
+		}
+
+		out, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("configgen: could not create %s: %w", outputFile, err)
+		}
+
+		tmpl, err := template.New(filepath.Base(templateFile)).Funcs(g.FuncMap).ParseFiles(templateFile)
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("configgen: could not parse template %s: %w", templateFile, err)
+		}
+		if err := tmpl.Execute(out, templateData{Env: profile.Name, Config: config}); err != nil {
+			out.Close()
+			return fmt.Errorf("configgen: could not render template %s: %w", templateFile, err)
+		}
+		if err := out.Close(); err != nil {
+			return fmt.Errorf("configgen: could not close %s: %w", outputFile, err)
+		}
+	}
+	return nil
+}
+
+// templateData is the top-level value passed to every rendered template.
+type templateData struct {
+	Env    string
+	Config interface{}
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package configgen
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// confirm prompts the user on stdin/stdout with a y/n question, re-prompting on
+// anything but "y" or "n".
+func confirm(prompt string) (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print(prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return false, fmt.Errorf("configgen: reading stdin failed: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y":
+			return true, nil
+		case "n":
+			return false, nil
+		default:
+			fmt.Printf("Entered %q. Please only use \"y\" or \"n\".\n", strings.TrimSpace(line))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package configgen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SkaffoldExecutor runs `skaffold dev|run` against the generated skaffold file
+// for a profile, matching the behavior of the original skaffold_template.go.
+type SkaffoldExecutor struct {
+	// SubCommand is "dev" or "run".
+	SubCommand string
+	// WorkspaceDir is the directory skaffold is invoked from.
+	WorkspaceDir string
+}
+
+// Execute implements Executor.
+func (e SkaffoldExecutor) Execute(outputDir string, profile EnvironmentProfile) error {
+	// renderTemplates flattens every template's basename into outputDir and
+	// suffixes it with the profile's Deployment, e.g. "skaffold_<Deployment>.yaml"
+	// directly under outputDir (no "skaffold/" subdir). Resolve the same path here.
+	skaffoldFile := path.Join(outputDir, fmt.Sprintf("skaffold_%s.yaml", profile.Deployment))
+	if _, err := os.Stat(skaffoldFile); err != nil {
+		return fmt.Errorf("configgen: can't find skaffold file %s: %w", skaffoldFile, err)
+	}
+
+	cmd := exec.Command("skaffold", e.SubCommand, "-f", skaffoldFile)
+	cmd.Dir = e.WorkspaceDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// KubectlExecutor runs `kubectl apply -f` against the generated output directory.
+type KubectlExecutor struct {
+	Namespace string
+}
+
+// Execute implements Executor.
+func (e KubectlExecutor) Execute(outputDir string, profile EnvironmentProfile) error {
+	args := []string{"apply", "-f", outputDir}
+	if e.Namespace != "" {
+		args = append(args, "-n", e.Namespace)
+	}
+	cmd := exec.Command("kubectl", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// DryRunExecutor logs what it would have executed instead of doing anything.
+type DryRunExecutor struct{}
+
+// Execute implements Executor.
+func (DryRunExecutor) Execute(outputDir string, profile EnvironmentProfile) error {
+	log.Infof("dry-run: would apply generated configs in %s for profile %q", outputDir, profile.Name)
+	return nil
+}
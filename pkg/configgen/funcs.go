@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package configgen
+
+import (
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs returns the default sprig-style helper functions made available
+// to every template rendered by a Generator, e.g.
+// {{ "vizier-metadata" | tagFor .Env }}. Templates are given only .Env and
+// .Config (see templateData); there is no top-level .Image field.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"tagFor":    tagFor,
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"trimSpace": strings.TrimSpace,
+		"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	}
+}
+
+// tagFor suffixes image with the current environment name, e.g.
+// tagFor("staging", "vizier-metadata") -> "vizier-metadata:staging".
+func tagFor(env, image string) string {
+	return image + ":" + env
+}
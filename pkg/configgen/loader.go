@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package configgen
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"gopkg.in/yaml.v2"
+
+	pb "pixielabs.ai/pixielabs/templates/skaffold/proto"
+)
+
+// PbtxtLoader loads a pb.ServiceConfig from a pbtxt file, matching the format
+// the original skaffold_template.go script consumed.
+type PbtxtLoader struct{}
+
+// Load implements ConfigLoader.
+func (PbtxtLoader) Load(path string) (interface{}, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file %s: %w", path, err)
+	}
+	config := &pb.ServiceConfig{}
+	if err := proto.UnmarshalText(string(buf), config); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal pbtxt config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// YAMLLoader loads a config file as arbitrary YAML, for environments that don't
+// need the structure of pb.ServiceConfig.
+type YAMLLoader struct{}
+
+// Load implements ConfigLoader.
+func (YAMLLoader) Load(path string) (interface{}, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file %s: %w", path, err)
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(buf, &config); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal yaml config %s: %w", path, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package configgen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// manifestProfile mirrors EnvironmentProfile's shape in the on-disk manifest
+// format; it's unmarshaled and then converted, keeping the YAML tags out of the
+// public type.
+type manifestProfile struct {
+	ConfigFile          string `yaml:"configFile"`
+	Deployment          string `yaml:"deployment"`
+	RequireConfirmation bool   `yaml:"requireConfirmation"`
+}
+
+// LoadManifest reads a YAML file mapping profile name to its settings, e.g.:
+//
+//	dev:
+//	  configFile: skaffold_service_config_dev.pbtxt
+//	  deployment: dev
+//	staging:
+//	  configFile: skaffold_service_config_staging.pbtxt
+//	  deployment: staging
+//	  requireConfirmation: true
+//	my_custom_env:
+//	  configFile: my_custom_env.pbtxt
+//	  deployment: my_custom_env
+//	  requireConfirmation: true
+//
+// Custom environments need nothing beyond an entry here; there is no hard-coded
+// dev/staging/prod switch.
+func LoadManifest(path string) (map[string]EnvironmentProfile, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("configgen: cannot read manifest %s: %w", path, err)
+	}
+
+	var raw map[string]manifestProfile
+	if err := yaml.Unmarshal(buf, &raw); err != nil {
+		return nil, fmt.Errorf("configgen: cannot unmarshal manifest %s: %w", path, err)
+	}
+
+	profiles := make(map[string]EnvironmentProfile, len(raw))
+	for name, p := range raw {
+		configFile := p.ConfigFile
+		if configFile != "" && !filepath.IsAbs(configFile) {
+			configFile = filepath.Join(filepath.Dir(path), configFile)
+		}
+		profiles[name] = EnvironmentProfile{
+			Name:                name,
+			ConfigFile:          configFile,
+			Deployment:          p.Deployment,
+			RequireConfirmation: p.RequireConfirmation,
+		}
+	}
+	return profiles, nil
+}
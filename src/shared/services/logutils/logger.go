@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package logutils provides a small wrapper around logrus for attaching
+// per-request, leveled, contextual loggers to a context.Context, so that
+// RPC handlers can log entry/exit with fields instead of ad-hoc Fatalf calls.
+package logutils
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKeyType string
+
+const loggerContextKey contextKeyType = "logutils.logger"
+
+// Format selects the output encoding used by NewLogger.
+type Format string
+
+const (
+	// FormatJSON emits one JSON object per log line, suitable for log aggregators.
+	FormatJSON Format = "json"
+	// FormatConsole emits human-readable, colorized lines, suitable for local dev.
+	FormatConsole Format = "console"
+)
+
+// NewLogger creates a root logrus.Logger configured with the given output format.
+func NewLogger(format Format) *logrus.Logger {
+	l := logrus.New()
+	switch format {
+	case FormatConsole:
+		l.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		l.SetFormatter(&logrus.JSONFormatter{})
+	}
+	return l
+}
+
+// NewContext returns a copy of ctx carrying entry as its logger. Use
+// LoggerFromContext to retrieve it.
+func NewContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerContextKey, entry)
+}
+
+// LoggerFromContext returns the logger attached to ctx by NewContext, or a
+// standalone entry from logrus's default logger if none was attached.
+func LoggerFromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(loggerContextKey).(*logrus.Entry); ok && entry != nil {
+		return entry
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// WithFields attaches a logger carrying the given fields to ctx, replacing any
+// logger already present. It's a convenience for handlers that want to add
+// fields without re-deriving the whole entry.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return NewContext(ctx, LoggerFromContext(ctx).WithFields(fields))
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package logutils
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that injects a
+// per-request logger (retrievable via LoggerFromContext) carrying a request ID
+// and the RPC method name, and logs entry/exit with latency and an error
+// classification.
+func UnaryServerInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		reqID := requestIDFromContext(ctx)
+		entry := logger.WithFields(logrus.Fields{
+			"requestID": reqID,
+			"method":    info.FullMethod,
+		})
+		ctx = NewContext(ctx, entry)
+
+		start := time.Now()
+		entry.Debug("rpc started")
+
+		resp, err := handler(ctx, req)
+
+		fields := logrus.Fields{"latency": time.Since(start)}
+		if err != nil {
+			fields["code"] = status.Code(err).String()
+			entry.WithFields(fields).WithError(err).Error("rpc finished with error")
+		} else {
+			fields["code"] = codes.OK.String()
+			entry.WithFields(fields).Debug("rpc finished")
+		}
+
+		return resp, err
+	}
+}
+
+// requestIDFromContext returns the request ID stored in ctx by
+// NewRequestIDContext, generating a fresh UUID if none is found. No other
+// context key is consulted.
+func requestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDContextKey).(string); ok && id != "" {
+		return id
+	}
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+type requestIDContextKeyType string
+
+const requestIDContextKey requestIDContextKeyType = "logutils.requestID"
+
+// NewRequestIDContext returns a copy of ctx carrying the given request ID, for
+// use by callers (e.g. an upstream gateway) that already generated one.
+func NewRequestIDContext(ctx context.Context, reqID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, reqID)
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ScriptRunResult is the output of a single cron script run, handed to every
+// configured ResultSink after the run finishes.
+type ScriptRunResult struct {
+	ScriptID  uuid.UUID
+	StartTime time.Time
+	EndTime   time.Time
+	Status    RunStatus
+	Error     string
+	// Tables holds the run's output tables, keyed by table name, encoded in
+	// whatever wire format the query execution layer produced.
+	Tables map[string][]byte
+}
+
+// ResultSink delivers a ScriptRunResult somewhere durable: Elasticsearch, a NATS
+// JetStream stream, a GCS bucket, etc. Publish should be fast and non-blocking
+// from the caller's perspective; slow sinks should be wrapped in a BufferedSink.
+type ResultSink interface {
+	Publish(ctx context.Context, result ScriptRunResult) error
+}
+
+// QueryableResultSink is implemented by sinks that can also serve
+// GetScriptRunResults reads back out of durable storage.
+type QueryableResultSink interface {
+	ResultSink
+	Query(ctx context.Context, scriptID uuid.UUID, since time.Time) ([]ScriptRunResult, error)
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultSinkQueueSize bounds how many pending results a BufferedSink holds
+// before it starts dropping the oldest one to make room for new writes.
+const defaultSinkQueueSize = 256
+
+// BufferedSink wraps a ResultSink with a bounded, drop-oldest queue, so a slow
+// or unavailable backend can't block script execution. Deliveries that the
+// underlying sink fails are forwarded to deadLetter, if set.
+type BufferedSink struct {
+	name       string
+	underlying ResultSink
+	deadLetter ResultSink
+
+	mu      sync.Mutex
+	queue   []ScriptRunResult
+	maxSize int
+	wake    chan struct{}
+
+	dropped uint64
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewBufferedSink wraps underlying with a bounded queue of maxSize (or
+// defaultSinkQueueSize if <= 0) and starts a background goroutine draining it.
+// deadLetter may be nil.
+func NewBufferedSink(name string, underlying ResultSink, maxSize int, deadLetter ResultSink) *BufferedSink {
+	if maxSize <= 0 {
+		maxSize = defaultSinkQueueSize
+	}
+	b := &BufferedSink{
+		name:       name,
+		underlying: underlying,
+		deadLetter: deadLetter,
+		maxSize:    maxSize,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	go b.drain()
+	return b
+}
+
+// Publish enqueues result for delivery, dropping the oldest queued result if
+// the queue is already full.
+func (b *BufferedSink) Publish(ctx context.Context, result ScriptRunResult) error {
+	b.mu.Lock()
+	if len(b.queue) >= b.maxSize {
+		b.queue = b.queue[1:]
+		atomic.AddUint64(&b.dropped, 1)
+		log.WithField("sink", b.name).Warn("result sink queue full, dropping oldest result")
+	}
+	b.queue = append(b.queue, result)
+	b.mu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Dropped returns the number of results dropped so far because the queue was full.
+func (b *BufferedSink) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Stop stops the background drain goroutine.
+func (b *BufferedSink) Stop() {
+	b.once.Do(func() { close(b.done) })
+}
+
+func (b *BufferedSink) drain() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-b.wake:
+			b.drainOnce()
+		}
+	}
+}
+
+func (b *BufferedSink) drainOnce() {
+	for {
+		b.mu.Lock()
+		if len(b.queue) == 0 {
+			b.mu.Unlock()
+			return
+		}
+		result := b.queue[0]
+		b.queue = b.queue[1:]
+		b.mu.Unlock()
+
+		if err := b.underlying.Publish(context.Background(), result); err != nil {
+			log.WithError(err).WithField("sink", b.name).Error("failed to publish result, sending to dead letter sink")
+			if b.deadLetter != nil {
+				if dlErr := b.deadLetter.Publish(context.Background(), result); dlErr != nil {
+					log.WithError(dlErr).WithField("sink", b.name).Error("failed to publish result to dead letter sink")
+				}
+			}
+		}
+	}
+}
+
+// queryableBufferedSink is a BufferedSink whose underlying sink also supports
+// QueryableResultSink; it forwards Query directly to the underlying sink so
+// wrapping a sink for backpressure doesn't take away its query support.
+type queryableBufferedSink struct {
+	*BufferedSink
+	queryable QueryableResultSink
+}
+
+// Query implements QueryableResultSink by delegating to the underlying sink.
+func (q *queryableBufferedSink) Query(ctx context.Context, scriptID uuid.UUID, since time.Time) ([]ScriptRunResult, error) {
+	return q.queryable.Query(ctx, scriptID, since)
+}
+
+// bufferResultSink wraps sink in a BufferedSink backed by deadLetter, preserving
+// QueryableResultSink support if sink implements it.
+func bufferResultSink(name string, sink ResultSink, deadLetter ResultSink) ResultSink {
+	buffered := NewBufferedSink(name, sink, defaultSinkQueueSize, deadLetter)
+	if qs, ok := sink.(QueryableResultSink); ok {
+		return &queryableBufferedSink{BufferedSink: buffered, queryable: qs}
+	}
+	return buffered
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"sync"
+)
+
+// DeadLetterSink retains results that a configured backend failed to deliver,
+// one entry per failed delivery (a result that several backends fail to
+// deliver is retained once per failing backend), so operators can inspect or
+// manually replay them. It is itself a
+// ResultSink so it composes with BufferedSink like any other backend.
+type DeadLetterSink struct {
+	mu      sync.Mutex
+	results []ScriptRunResult
+}
+
+// NewDeadLetterSink creates an empty DeadLetterSink.
+func NewDeadLetterSink() *DeadLetterSink {
+	return &DeadLetterSink{}
+}
+
+// Publish implements ResultSink by retaining result in memory.
+func (d *DeadLetterSink) Publish(ctx context.Context, result ScriptRunResult) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.results = append(d.results, result)
+	return nil
+}
+
+// Results returns a copy of all results retained so far.
+func (d *DeadLetterSink) Results() []ScriptRunResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]ScriptRunResult, len(d.results))
+	copy(out, d.results)
+	return out
+}
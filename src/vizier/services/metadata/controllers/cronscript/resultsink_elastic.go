@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/olivere/elastic/v7"
+)
+
+// defaultElasticIndex is used when ElasticResultSink isn't given an explicit one.
+const defaultElasticIndex = "pl-cronscript-run-results"
+
+// elasticDoc is the document shape indexed for each run result.
+type elasticDoc struct {
+	ScriptID  string            `json:"script_id"`
+	StartTime time.Time         `json:"start_time"`
+	EndTime   time.Time         `json:"end_time"`
+	Status    string            `json:"status"`
+	Error     string            `json:"error,omitempty"`
+	Tables    map[string][]byte `json:"tables,omitempty"`
+}
+
+// ElasticResultSink publishes run results as documents in an Elasticsearch index.
+type ElasticResultSink struct {
+	client *elastic.Client
+	index  string
+}
+
+// NewElasticResultSink creates an ElasticResultSink that writes to index (or
+// defaultElasticIndex if empty).
+func NewElasticResultSink(client *elastic.Client, index string) *ElasticResultSink {
+	if index == "" {
+		index = defaultElasticIndex
+	}
+	return &ElasticResultSink{client: client, index: index}
+}
+
+// Publish implements ResultSink.
+func (e *ElasticResultSink) Publish(ctx context.Context, result ScriptRunResult) error {
+	doc := elasticDoc{
+		ScriptID:  result.ScriptID.String(),
+		StartTime: result.StartTime,
+		EndTime:   result.EndTime,
+		Status:    statusString(result.Status),
+		Error:     result.Error,
+		Tables:    result.Tables,
+	}
+	_, err := e.client.Index().
+		Index(e.index).
+		BodyJson(doc).
+		Do(ctx)
+	return err
+}
+
+// Query implements QueryableResultSink by searching for documents with the
+// given script ID and a start time at or after since.
+func (e *ElasticResultSink) Query(ctx context.Context, scriptID uuid.UUID, since time.Time) ([]ScriptRunResult, error) {
+	query := elastic.NewBoolQuery().
+		Must(elastic.NewTermQuery("script_id", scriptID.String())).
+		Must(elastic.NewRangeQuery("start_time").Gte(since))
+
+	resp, err := e.client.Search().
+		Index(e.index).
+		Query(query).
+		Sort("start_time", false).
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cronscript: elasticsearch query failed: %w", err)
+	}
+
+	results := make([]ScriptRunResult, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		var doc elasticDoc
+		if err := json.Unmarshal(hit.Source, &doc); err != nil {
+			continue
+		}
+		results = append(results, ScriptRunResult{
+			ScriptID:  scriptID,
+			StartTime: doc.StartTime,
+			EndTime:   doc.EndTime,
+			Status:    runStatusFromString(doc.Status),
+			Error:     doc.Error,
+			Tables:    doc.Tables,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/gofrs/uuid"
+	"google.golang.org/api/iterator"
+)
+
+// GCSResultSink writes each run result as a JSON object to a GCS bucket, keyed
+// by script ID and start time so Query can list by prefix.
+type GCSResultSink struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+// NewGCSResultSink creates a GCSResultSink writing objects under prefix (or the
+// bucket root if empty) in bucket.
+func NewGCSResultSink(bucket *storage.BucketHandle, prefix string) *GCSResultSink {
+	return &GCSResultSink{bucket: bucket, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+func (g *GCSResultSink) objectName(scriptID uuid.UUID, start time.Time) string {
+	name := fmt.Sprintf("%s/%020d.json", scriptID.String(), start.UnixNano())
+	if g.prefix == "" {
+		return name
+	}
+	return g.prefix + "/" + name
+}
+
+// Publish implements ResultSink by writing result as a JSON object.
+func (g *GCSResultSink) Publish(ctx context.Context, result ScriptRunResult) error {
+	w := g.bucket.Object(g.objectName(result.ScriptID, result.StartTime)).NewWriter(ctx)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		w.Close()
+		return fmt.Errorf("cronscript: failed to encode result for gcs: %w", err)
+	}
+	return w.Close()
+}
+
+// Query implements QueryableResultSink by listing objects under the script's
+// prefix and filtering by object name (which encodes the start time).
+func (g *GCSResultSink) Query(ctx context.Context, scriptID uuid.UUID, since time.Time) ([]ScriptRunResult, error) {
+	scriptPrefix := scriptID.String() + "/"
+	if g.prefix != "" {
+		scriptPrefix = g.prefix + "/" + scriptPrefix
+	}
+
+	it := g.bucket.Objects(ctx, &storage.Query{Prefix: scriptPrefix})
+	var results []ScriptRunResult
+	for {
+		objAttrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cronscript: failed to list gcs objects: %w", err)
+		}
+		startNs, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(objAttrs.Name, scriptPrefix), ".json"), 10, 64)
+		if err != nil || time.Unix(0, startNs).Before(since) {
+			continue
+		}
+
+		r, err := g.bucket.Object(objAttrs.Name).NewReader(ctx)
+		if err != nil {
+			continue
+		}
+		var result ScriptRunResult
+		err = json.NewDecoder(r).Decode(&result)
+		r.Close()
+		if err != nil {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
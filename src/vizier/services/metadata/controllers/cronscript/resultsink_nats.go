@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// defaultResultsStreamSubject is the subject run results are published to; the
+// caller is expected to have already created a JetStream stream bound to it
+// (e.g. "px.cronscript.results.>").
+const defaultResultsStreamSubject = "px.cronscript.results"
+
+// natsResultDoc is the wire format published to the results stream.
+type natsResultDoc struct {
+	ScriptID  string            `json:"script_id"`
+	StartTime int64             `json:"start_time_unix_ns"`
+	EndTime   int64             `json:"end_time_unix_ns"`
+	Status    string            `json:"status"`
+	Error     string            `json:"error,omitempty"`
+	Tables    map[string][]byte `json:"tables,omitempty"`
+}
+
+// NATSResultSink publishes run results onto a NATS JetStream stream, so any
+// number of downstream consumers can replay them.
+type NATSResultSink struct {
+	js      nats.JetStreamContext
+	subject string
+}
+
+// NewNATSResultSink creates a NATSResultSink that publishes to subject (or
+// defaultResultsStreamSubject if empty) via js.
+func NewNATSResultSink(js nats.JetStreamContext, subject string) *NATSResultSink {
+	if subject == "" {
+		subject = defaultResultsStreamSubject
+	}
+	return &NATSResultSink{js: js, subject: subject}
+}
+
+// Publish implements ResultSink.
+func (n *NATSResultSink) Publish(ctx context.Context, result ScriptRunResult) error {
+	doc := natsResultDoc{
+		ScriptID:  result.ScriptID.String(),
+		StartTime: result.StartTime.UnixNano(),
+		EndTime:   result.EndTime.UnixNano(),
+		Status:    statusString(result.Status),
+		Error:     result.Error,
+		Tables:    result.Tables,
+	}
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("cronscript: failed to marshal result for nats: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s.%s", n.subject, result.ScriptID.String())
+	_, err = n.js.Publish(subject, payload, nats.Context(ctx))
+	return err
+}
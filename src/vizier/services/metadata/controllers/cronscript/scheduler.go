@@ -0,0 +1,519 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+// RunStatus describes the terminal (or in-progress) state of a single script run.
+type RunStatus int
+
+const (
+	// RunStatusUnknown is the zero value and should never be persisted.
+	RunStatusUnknown RunStatus = iota
+	// RunStatusRunning means the script is currently executing.
+	RunStatusRunning
+	// RunStatusSucceeded means the script finished without error.
+	RunStatusSucceeded
+	// RunStatusFailed means the script finished with an error.
+	RunStatusFailed
+)
+
+// RunRecord captures the result of a single execution of a cron script.
+type RunRecord struct {
+	ScriptID  uuid.UUID
+	StartTime time.Time
+	EndTime   time.Time
+	Status    RunStatus
+	Error     string
+}
+
+// StoreEventKind identifies the type of change delivered by Store.Watch.
+type StoreEventKind int
+
+const (
+	// StoreEventUpsert means a script was added or updated.
+	StoreEventUpsert StoreEventKind = iota
+	// StoreEventDelete means a script was removed.
+	StoreEventDelete
+	// StoreEventSetAll means the entire script set was replaced.
+	StoreEventSetAll
+)
+
+// StoreEvent is delivered over the channel returned by Store.Watch whenever
+// the underlying set of cron scripts changes.
+type StoreEvent struct {
+	Kind     StoreEventKind
+	Script   *cvmsgspb.CronScript
+	ScriptID uuid.UUID
+	Scripts  []*cvmsgspb.CronScript
+}
+
+const (
+	// cronScriptRunStartedSubject is the NATS subject a message is published to when a
+	// scheduled run starts. The subject is fixed; the script's UUID is carried in
+	// the message payload.
+	cronScriptRunStartedSubject = "cronscript.run.started"
+	// cronScriptRunFinishedSubject is the NATS subject a message is published to when a
+	// scheduled run finishes, regardless of outcome.
+	cronScriptRunFinishedSubject = "cronscript.run.finished"
+
+	// maxSchedulerJitter bounds the random jitter added to each run interval so that
+	// scripts sharing a frequency don't all fire at the same instant (thundering herd).
+	maxSchedulerJitter = 5 * time.Second
+
+	// defaultRunHistoryLimit is used when a caller asks for run history without
+	// specifying how many records to return.
+	defaultRunHistoryLimit = 20
+)
+
+// ErrScriptNotScheduled is returned when an operation is requested on a script ID
+// that the Scheduler does not currently know about.
+var ErrScriptNotScheduled = errors.New("script is not currently scheduled")
+
+// runEvent is published on the NATS subjects above when a run starts or finishes.
+type runEvent struct {
+	ScriptID string    `json:"script_id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// scheduledScript tracks the live scheduling state for a single CronScript.
+type scheduledScript struct {
+	mu      sync.Mutex
+	script  *cvmsgspb.CronScript
+	paused  bool
+	running bool
+	cancel  chan struct{}
+	trigger chan struct{}
+}
+
+// ScriptExecutor runs a CronScript's query body and returns its output tables,
+// keyed by table name and encoded in whatever wire format the query execution
+// layer produces. It is the extension point that hands a scheduled run off to
+// the query broker.
+type ScriptExecutor interface {
+	ExecuteScript(ctx context.Context, script *cvmsgspb.CronScript) (map[string][]byte, error)
+}
+
+// Scheduler runs cron scripts on their configured frequency, enforcing per-script
+// concurrency, persisting run results back to the Store, and publishing NATS
+// notifications when runs start and finish. It keeps itself in sync with the Store
+// by consuming the channel returned by Store.Watch, so callers don't need to
+// restart the process for AddOrUpdateScript/DeleteScript/SetScripts to take effect.
+//
+// Scheduling is frequency-based only: each script's FrequencyS is treated as a
+// fixed interval with jitter. Cron-expression scheduling is not supported yet,
+// since cvmsgspb.CronScript has no field to carry one.
+type Scheduler struct {
+	ds         Store
+	nc         *nats.Conn
+	executor   ScriptExecutor
+	sinks      []ResultSink
+	deadLetter *DeadLetterSink
+
+	mu      sync.Mutex
+	scripts map[uuid.UUID]*scheduledScript
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewScheduler creates a Scheduler backed by the given Store. If nc is non-nil, run
+// start/finish events are published to it. executor hands off actual script
+// execution to the query broker; a nil executor means every run fails with a
+// descriptive error rather than silently reporting success. Each sink is wrapped
+// in a BufferedSink so a slow or unavailable backend can't block a run from
+// completing; every delivery that any one sink fails to make is retained in a
+// shared DeadLetterSink, available via DeadLetter().
+func NewScheduler(ds Store, nc *nats.Conn, executor ScriptExecutor, sinks ...ResultSink) *Scheduler {
+	deadLetter := NewDeadLetterSink()
+	buffered := make([]ResultSink, len(sinks))
+	for i, sink := range sinks {
+		buffered[i] = bufferResultSink(fmt.Sprintf("%T-%d", sink, i), sink, deadLetter)
+	}
+
+	return &Scheduler{
+		ds:         ds,
+		nc:         nc,
+		executor:   executor,
+		sinks:      buffered,
+		deadLetter: deadLetter,
+		scripts:    make(map[uuid.UUID]*scheduledScript),
+		done:       make(chan struct{}),
+	}
+}
+
+// DeadLetter returns the Scheduler's shared dead-letter sink, holding every
+// result that one of its configured sinks failed to deliver.
+func (s *Scheduler) DeadLetter() *DeadLetterSink {
+	return s.deadLetter
+}
+
+// Start loads the current set of scripts from the Store, schedules them, and begins
+// watching for live updates. It returns once the initial load is complete; ongoing
+// sync happens in a background goroutine until Stop is called. Start does not use
+// the context passed in: the initial load is not bounded by it, and Stop (not ctx's
+// cancellation) owns shutting down the Watch subscription.
+func (s *Scheduler) Start(ctx context.Context) error {
+	scripts, err := s.ds.GetCronScripts()
+	if err != nil {
+		return err
+	}
+	for _, script := range scripts {
+		s.schedule(script)
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	updates := s.ds.Watch(s.ctx)
+	go s.watchUpdates(updates)
+	return nil
+}
+
+// Stop cancels the Store.Watch subscription, cancels all scheduled runs, and
+// stops watching for Store updates.
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, ss := range s.scripts {
+		close(ss.cancel)
+		delete(s.scripts, id)
+	}
+}
+
+func (s *Scheduler) watchUpdates(updates <-chan StoreEvent) {
+	for {
+		select {
+		case <-s.done:
+			return
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			s.applyUpdate(u)
+		}
+	}
+}
+
+func (s *Scheduler) applyUpdate(u StoreEvent) {
+	switch u.Kind {
+	case StoreEventUpsert:
+		s.schedule(u.Script)
+	case StoreEventDelete:
+		s.unschedule(u.ScriptID)
+	case StoreEventSetAll:
+		s.mu.Lock()
+		keep := make(map[uuid.UUID]bool, len(u.Scripts))
+		for _, script := range u.Scripts {
+			keep[utils.UUIDFromProtoOrNil(script.ID)] = true
+		}
+		for id := range s.scripts {
+			if !keep[id] {
+				s.unscheduleLocked(id)
+			}
+		}
+		s.mu.Unlock()
+		for _, script := range u.Scripts {
+			s.schedule(script)
+		}
+	}
+}
+
+// schedule updates the script body for an already-scheduled script in place, or
+// starts its run loop for the first time. The run loop (and its timer) is only
+// restarted when FrequencyS actually changes, so an external watch event that
+// merely edits the script's query body doesn't reset the script's pending timer,
+// and a paused script stays paused across edits.
+func (s *Scheduler) schedule(script *cvmsgspb.CronScript) {
+	id := utils.UUIDFromProtoOrNil(script.ID)
+
+	s.mu.Lock()
+	existing, hadExisting := s.scripts[id]
+	var paused, sameFrequency bool
+	if hadExisting {
+		existing.mu.Lock()
+		sameFrequency = existing.script.FrequencyS == script.FrequencyS
+		paused = existing.paused
+		existing.script = script
+		existing.mu.Unlock()
+		if sameFrequency {
+			s.mu.Unlock()
+			return
+		}
+		close(existing.cancel)
+	}
+
+	ss := &scheduledScript{
+		script:  script,
+		paused:  paused,
+		cancel:  make(chan struct{}),
+		trigger: make(chan struct{}, 1),
+	}
+	s.scripts[id] = ss
+	s.mu.Unlock()
+
+	go s.runLoop(id, ss)
+}
+
+func (s *Scheduler) unschedule(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unscheduleLocked(id)
+}
+
+func (s *Scheduler) unscheduleLocked(id uuid.UUID) {
+	if ss, ok := s.scripts[id]; ok {
+		close(ss.cancel)
+		delete(s.scripts, id)
+	}
+}
+
+// runLoop fires runOnce on the script's configured frequency (plus jitter) until
+// cancel is closed. A paused script still ticks, it just skips execution.
+func (s *Scheduler) runLoop(id uuid.UUID, ss *scheduledScript) {
+	freq := time.Duration(ss.script.FrequencyS) * time.Second
+	if freq <= 0 {
+		log.WithField("scriptID", id.String()).Error("cron script has non-positive frequency, not scheduling")
+		return
+	}
+
+	timer := time.NewTimer(jitter(freq))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ss.cancel:
+			return
+		case <-ss.trigger:
+			s.runOnce(id, ss)
+		case <-timer.C:
+			ss.mu.Lock()
+			paused := ss.paused
+			ss.mu.Unlock()
+			if !paused {
+				s.runOnce(id, ss)
+			}
+			timer.Reset(jitter(freq))
+		}
+	}
+}
+
+// jitter returns d plus a random amount up to maxSchedulerJitter, to spread out
+// scripts that share the same frequency.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(maxSchedulerJitter)))
+}
+
+// runOnce executes the script exactly once, skipping if a prior run is still in
+// flight, and records/publishes the result.
+func (s *Scheduler) runOnce(id uuid.UUID, ss *scheduledScript) {
+	ss.mu.Lock()
+	if ss.running {
+		ss.mu.Unlock()
+		log.WithField("scriptID", id.String()).Warn("skipping cron script run, prior run still executing")
+		return
+	}
+	ss.running = true
+	script := ss.script
+	ss.mu.Unlock()
+
+	defer func() {
+		ss.mu.Lock()
+		ss.running = false
+		ss.mu.Unlock()
+	}()
+
+	record := &RunRecord{
+		ScriptID:  id,
+		StartTime: time.Now(),
+		Status:    RunStatusRunning,
+	}
+	s.publishRunEvent(cronScriptRunStartedSubject, record)
+
+	tables, err := s.execute(script)
+
+	record.EndTime = time.Now()
+	if err != nil {
+		record.Status = RunStatusFailed
+		record.Error = err.Error()
+	} else {
+		record.Status = RunStatusSucceeded
+	}
+
+	if rerr := s.ds.RecordRun(id, record); rerr != nil {
+		log.WithError(rerr).WithField("scriptID", id.String()).Error("failed to record cron script run")
+	}
+	s.publishRunEvent(cronScriptRunFinishedSubject, record)
+	s.publishResult(ScriptRunResult{
+		ScriptID:  record.ScriptID,
+		StartTime: record.StartTime,
+		EndTime:   record.EndTime,
+		Status:    record.Status,
+		Error:     record.Error,
+		Tables:    tables,
+	})
+}
+
+// publishResult hands result to every configured sink.
+func (s *Scheduler) publishResult(result ScriptRunResult) {
+	for _, sink := range s.sinks {
+		if err := sink.Publish(context.Background(), result); err != nil {
+			log.WithError(err).WithField("scriptID", result.ScriptID.String()).Error("failed to publish cron script run result")
+		}
+	}
+}
+
+// execute is the actual script invocation. It is factored out so that result
+// delivery (see the ResultSink abstraction) can be layered on top of it.
+func (s *Scheduler) execute(script *cvmsgspb.CronScript) (map[string][]byte, error) {
+	if script.Script == "" {
+		return nil, fmt.Errorf("cron script has no query body")
+	}
+	if s.executor == nil {
+		return nil, fmt.Errorf("cronscript: no ScriptExecutor configured, cannot execute script query")
+	}
+	return s.executor.ExecuteScript(context.Background(), script)
+}
+
+func (s *Scheduler) publishRunEvent(subject string, record *RunRecord) {
+	if s.nc == nil {
+		return
+	}
+	ev := runEvent{
+		ScriptID: record.ScriptID.String(),
+		Start:    record.StartTime,
+		End:      record.EndTime,
+		Status:   statusString(record.Status),
+		Error:    record.Error,
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.WithError(err).WithField("scriptID", record.ScriptID.String()).Error("failed to marshal cron script run event")
+		return
+	}
+	if err := s.nc.Publish(subject, payload); err != nil {
+		log.WithError(err).WithField("scriptID", record.ScriptID.String()).Error("failed to publish cron script run event")
+	}
+}
+
+func statusString(st RunStatus) string {
+	switch st {
+	case RunStatusRunning:
+		return "running"
+	case RunStatusSucceeded:
+		return "succeeded"
+	case RunStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// TriggerNow schedules an immediate, out-of-band run of the given script, without
+// disturbing its regular schedule.
+func (s *Scheduler) TriggerNow(id uuid.UUID) error {
+	s.mu.Lock()
+	ss, ok := s.scripts[id]
+	s.mu.Unlock()
+	if !ok {
+		return ErrScriptNotScheduled
+	}
+	select {
+	case ss.trigger <- struct{}{}:
+	default:
+		// A trigger is already pending; runOnce's concurrency guard makes a second
+		// enqueue redundant.
+	}
+	return nil
+}
+
+// Pause stops future scheduled runs for the given script until Resume is called.
+// An in-flight run is allowed to finish.
+func (s *Scheduler) Pause(id uuid.UUID) error {
+	s.mu.Lock()
+	ss, ok := s.scripts[id]
+	s.mu.Unlock()
+	if !ok {
+		return ErrScriptNotScheduled
+	}
+	ss.mu.Lock()
+	ss.paused = true
+	ss.mu.Unlock()
+	return nil
+}
+
+// Resume re-enables scheduled runs for a previously paused script.
+func (s *Scheduler) Resume(id uuid.UUID) error {
+	s.mu.Lock()
+	ss, ok := s.scripts[id]
+	s.mu.Unlock()
+	if !ok {
+		return ErrScriptNotScheduled
+	}
+	ss.mu.Lock()
+	ss.paused = false
+	ss.mu.Unlock()
+	return nil
+}
+
+// RunHistory returns up to limit of the most recent run records for the script,
+// delegating to the Store. A limit <= 0 uses defaultRunHistoryLimit.
+func (s *Scheduler) RunHistory(id uuid.UUID, limit int) ([]*RunRecord, error) {
+	if limit <= 0 {
+		limit = defaultRunHistoryLimit
+	}
+	return s.ds.GetRunHistory(id, limit)
+}
+
+// RunResults returns the run results recorded since the given time, reading
+// from the first configured sink that supports querying. It returns an error if
+// no configured sink is queryable.
+func (s *Scheduler) RunResults(ctx context.Context, id uuid.UUID, since time.Time) ([]ScriptRunResult, error) {
+	for _, sink := range s.sinks {
+		if qs, ok := sink.(QueryableResultSink); ok {
+			return qs.Query(ctx, id, since)
+		}
+	}
+	return nil, fmt.Errorf("cronscript: no queryable result sink is configured")
+}
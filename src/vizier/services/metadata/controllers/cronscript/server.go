@@ -20,11 +20,18 @@ package cronscript
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/shared/services/logutils"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
 )
@@ -35,21 +42,46 @@ type Store interface {
 	UpsertCronScript(script *cvmsgspb.CronScript) error
 	DeleteCronScript(id uuid.UUID) error
 	SetCronScripts(scripts []*cvmsgspb.CronScript) error
+
+	// RecordRun persists the outcome of a single execution of the given script.
+	RecordRun(id uuid.UUID, run *RunRecord) error
+	// GetRunHistory returns up to limit of the most recent run records for the script,
+	// newest first.
+	GetRunHistory(id uuid.UUID, limit int) ([]*RunRecord, error)
+
+	// Watch returns a channel of updates to the stored script set. The channel is
+	// closed by the implementation once ctx is canceled, or right away by an
+	// implementation that has no way to watch (e.g. a PostgresStore without a
+	// listener); callers do not close the returned channel.
+	Watch(ctx context.Context) <-chan StoreEvent
 }
 
 // Server is an implementation of the cronscriptstore service.
 type Server struct {
-	ds Store
+	ds        Store
+	scheduler *Scheduler
 
 	done chan struct{}
 	once sync.Once
 }
 
-// New creates a new server.
-func New(ds Store) *Server {
+// New creates a new server and starts a Scheduler that executes the stored cron
+// scripts on their configured frequency. nc may be nil, in which case run events
+// are not published. executor hands scheduled runs off to the query broker; it
+// may be nil in deployments that only need the CRUD surface, but every run will
+// then fail with an explicit error rather than appearing to succeed. Each sink
+// receives a copy of every completed run's result; operators can fan out to
+// multiple backends by passing more than one.
+func New(ds Store, nc *nats.Conn, executor ScriptExecutor, sinks ...ResultSink) *Server {
+	scheduler := NewScheduler(ds, nc, executor, sinks...)
+	if err := scheduler.Start(context.Background()); err != nil {
+		log.WithError(err).Error("failed to start cron script scheduler")
+	}
+
 	return &Server{
-		ds:   ds,
-		done: make(chan struct{}),
+		ds:        ds,
+		scheduler: scheduler,
+		done:      make(chan struct{}),
 	}
 }
 
@@ -58,12 +90,18 @@ func (s *Server) Stop() {
 	s.once.Do(func() {
 		close(s.done)
 	})
+	s.scheduler.Stop()
 }
 
 // GetScripts fetches all scripts in the cron script store.
 func (s *Server) GetScripts(ctx context.Context, req *metadatapb.GetScriptsRequest) (*metadatapb.GetScriptsResponse, error) {
+	start := time.Now()
+	l := logutils.LoggerFromContext(ctx)
+	l.Debug("GetScripts entry")
+
 	scripts, err := s.ds.GetCronScripts()
 	if err != nil {
+		l.WithError(err).WithField("latency", time.Since(start)).Error("GetScripts failed")
 		return nil, err
 	}
 
@@ -73,6 +111,7 @@ func (s *Server) GetScripts(ctx context.Context, req *metadatapb.GetScriptsReque
 		scMap[id.String()] = s
 	}
 
+	l.WithFields(log.Fields{"latency": time.Since(start), "numScripts": len(scMap)}).Debug("GetScripts exit")
 	return &metadatapb.GetScriptsResponse{
 		Scripts: scMap,
 	}, nil
@@ -80,28 +119,133 @@ func (s *Server) GetScripts(ctx context.Context, req *metadatapb.GetScriptsReque
 
 // AddOrUpdateScript updates or adds a cron script to the store, based on ID.
 func (s *Server) AddOrUpdateScript(ctx context.Context, req *metadatapb.AddOrUpdateScriptRequest) (*metadatapb.AddOrUpdateScriptResponse, error) {
+	start := time.Now()
+	scriptID := utils.UUIDFromProtoOrNil(req.Script.ID)
+	l := logutils.LoggerFromContext(ctx).WithField("scriptID", scriptID.String())
+	l.Debug("AddOrUpdateScript entry")
+
 	err := s.ds.UpsertCronScript(req.Script)
 	if err != nil {
+		l.WithError(err).WithField("latency", time.Since(start)).Error("AddOrUpdateScript failed")
 		return nil, err
 	}
+	l.WithField("latency", time.Since(start)).Debug("AddOrUpdateScript exit")
 	return &metadatapb.AddOrUpdateScriptResponse{}, nil
 }
 
 // DeleteScript deletes a cron script from the store by ID.
 func (s *Server) DeleteScript(ctx context.Context, req *metadatapb.DeleteScriptRequest) (*metadatapb.DeleteScriptResponse, error) {
-	err := s.ds.DeleteCronScript(utils.UUIDFromProtoOrNil(req.ScriptID))
+	start := time.Now()
+	scriptID := utils.UUIDFromProtoOrNil(req.ScriptID)
+	l := logutils.LoggerFromContext(ctx).WithField("scriptID", scriptID.String())
+	l.Debug("DeleteScript entry")
+
+	err := s.ds.DeleteCronScript(scriptID)
 	if err != nil {
+		l.WithError(err).WithField("latency", time.Since(start)).Error("DeleteScript failed")
 		return nil, err
 	}
+	l.WithField("latency", time.Since(start)).Debug("DeleteScript exit")
 	return &metadatapb.DeleteScriptResponse{}, nil
 }
 
 // SetScripts sets the list of all cron scripts to match the given set of scripts.
 func (s *Server) SetScripts(ctx context.Context, req *metadatapb.SetScriptsRequest) (*metadatapb.SetScriptsResponse, error) {
+	start := time.Now()
+	l := logutils.LoggerFromContext(ctx)
+	l.Debug("SetScripts entry")
+
 	scripts := make([]*cvmsgspb.CronScript, 0)
 	for _, v := range req.Scripts {
 		scripts = append(scripts, v)
 	}
 
-	return &metadatapb.SetScriptsResponse{}, s.ds.SetCronScripts(scripts)
+	err := s.ds.SetCronScripts(scripts)
+	if err != nil {
+		l.WithError(err).WithField("latency", time.Since(start)).Error("SetScripts failed")
+		return nil, err
+	}
+	l.WithFields(log.Fields{"latency": time.Since(start), "numScripts": len(scripts)}).Debug("SetScripts exit")
+	return &metadatapb.SetScriptsResponse{}, nil
+}
+
+// TriggerScriptNow schedules an immediate, out-of-band run of the given script,
+// without disturbing its regular schedule.
+func (s *Server) TriggerScriptNow(ctx context.Context, req *metadatapb.TriggerScriptNowRequest) (*metadatapb.TriggerScriptNowResponse, error) {
+	if err := s.scheduler.TriggerNow(utils.UUIDFromProtoOrNil(req.ScriptID)); err != nil {
+		return nil, schedulerErrToStatus(err)
+	}
+	return &metadatapb.TriggerScriptNowResponse{}, nil
+}
+
+// PauseScript stops future scheduled runs for the given script until ResumeScript is called.
+func (s *Server) PauseScript(ctx context.Context, req *metadatapb.PauseScriptRequest) (*metadatapb.PauseScriptResponse, error) {
+	if err := s.scheduler.Pause(utils.UUIDFromProtoOrNil(req.ScriptID)); err != nil {
+		return nil, schedulerErrToStatus(err)
+	}
+	return &metadatapb.PauseScriptResponse{}, nil
+}
+
+// ResumeScript re-enables scheduled runs for a previously paused script.
+func (s *Server) ResumeScript(ctx context.Context, req *metadatapb.ResumeScriptRequest) (*metadatapb.ResumeScriptResponse, error) {
+	if err := s.scheduler.Resume(utils.UUIDFromProtoOrNil(req.ScriptID)); err != nil {
+		return nil, schedulerErrToStatus(err)
+	}
+	return &metadatapb.ResumeScriptResponse{}, nil
+}
+
+// GetScriptRunHistory returns recent run records for the given script, newest first.
+func (s *Server) GetScriptRunHistory(ctx context.Context, req *metadatapb.GetScriptRunHistoryRequest) (*metadatapb.GetScriptRunHistoryResponse, error) {
+	history, err := s.scheduler.RunHistory(utils.UUIDFromProtoOrNil(req.ScriptID), int(req.Limit))
+	if err != nil {
+		return nil, schedulerErrToStatus(err)
+	}
+
+	resp := &metadatapb.GetScriptRunHistoryResponse{
+		Runs: make([]*metadatapb.ScriptRunRecord, len(history)),
+	}
+	for i, r := range history {
+		resp.Runs[i] = &metadatapb.ScriptRunRecord{
+			ScriptID:  utils.ProtoFromUUID(r.ScriptID),
+			StartTime: r.StartTime.Unix(),
+			EndTime:   r.EndTime.Unix(),
+			Status:    statusString(r.Status),
+			Error:     r.Error,
+		}
+	}
+	return resp, nil
+}
+
+// GetScriptRunResults reads back recent run results for the given script from
+// whichever configured ResultSink supports querying, starting at since.
+func (s *Server) GetScriptRunResults(ctx context.Context, req *metadatapb.GetScriptRunResultsRequest) (*metadatapb.GetScriptRunResultsResponse, error) {
+	results, err := s.scheduler.RunResults(ctx, utils.UUIDFromProtoOrNil(req.ScriptID), time.Unix(req.Since, 0))
+	if err != nil {
+		return nil, schedulerErrToStatus(err)
+	}
+
+	resp := &metadatapb.GetScriptRunResultsResponse{
+		Results: make([]*metadatapb.ScriptRunResult, len(results)),
+	}
+	for i, r := range results {
+		resp.Results[i] = &metadatapb.ScriptRunResult{
+			ScriptID:  utils.ProtoFromUUID(r.ScriptID),
+			StartTime: r.StartTime.Unix(),
+			EndTime:   r.EndTime.Unix(),
+			Status:    statusString(r.Status),
+			Error:     r.Error,
+			Tables:    r.Tables,
+		}
+	}
+	return resp, nil
+}
+
+// schedulerErrToStatus maps Scheduler errors to gRPC status errors so that
+// "script isn't scheduled" surfaces as codes.NotFound instead of an opaque
+// Unknown error.
+func schedulerErrToStatus(err error) error {
+	if errors.Is(err, ErrScriptNotScheduled) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
 }
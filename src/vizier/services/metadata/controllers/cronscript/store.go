@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// StoreKind selects which Store implementation NewStore constructs.
+type StoreKind string
+
+const (
+	// StoreKindMemory is an in-process, non-durable Store. It's intended for tests
+	// and single-replica dev deployments.
+	StoreKindMemory StoreKind = "memory"
+	// StoreKindEtcd is an etcd-backed, durable Store shared across replicas.
+	StoreKindEtcd StoreKind = "etcd"
+	// StoreKindPostgres is a Postgres-backed, durable Store shared across replicas.
+	StoreKindPostgres StoreKind = "postgres"
+)
+
+// StoreConfig carries the configuration needed to construct any of the Store
+// implementations. Only the fields relevant to Kind need to be set.
+type StoreConfig struct {
+	Kind StoreKind
+
+	// EtcdClient is required when Kind is StoreKindEtcd.
+	EtcdClient EtcdClient
+	// EtcdPrefix namespaces all keys written by the etcd Store. Defaults to
+	// defaultEtcdPrefix if empty.
+	EtcdPrefix string
+
+	// PostgresDB is required when Kind is StoreKindPostgres.
+	PostgresDB SqlxDB
+	// PostgresListener is optional; when set, it backs Watch via LISTEN/NOTIFY.
+	PostgresListener *pq.Listener
+}
+
+// NewStore constructs a Store implementation selected by cfg.Kind.
+func NewStore(cfg StoreConfig) (Store, error) {
+	switch cfg.Kind {
+	case StoreKindMemory, "":
+		return NewMemoryStore(), nil
+	case StoreKindEtcd:
+		if cfg.EtcdClient == nil {
+			return nil, fmt.Errorf("cronscript: EtcdClient is required for StoreKindEtcd")
+		}
+		prefix := cfg.EtcdPrefix
+		if prefix == "" {
+			prefix = defaultEtcdPrefix
+		}
+		return NewEtcdStore(cfg.EtcdClient, prefix), nil
+	case StoreKindPostgres:
+		if cfg.PostgresDB == nil {
+			return nil, fmt.Errorf("cronscript: PostgresDB is required for StoreKindPostgres")
+		}
+		if cfg.PostgresListener != nil {
+			return NewPostgresStore(cfg.PostgresDB, cfg.PostgresListener), nil
+		}
+		return NewPostgresStore(cfg.PostgresDB), nil
+	default:
+		return nil, fmt.Errorf("cronscript: unknown StoreKind %q", cfg.Kind)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+// defaultEtcdPrefix namespaces all keys the etcd Store reads and writes.
+const defaultEtcdPrefix = "/pl/cronscript"
+
+// EtcdClient is the subset of *clientv3.Client the etcd Store depends on. It
+// exists so tests can substitute a fake without standing up a real etcd cluster.
+type EtcdClient interface {
+	clientv3.KV
+	clientv3.Watcher
+}
+
+// EtcdStore is an etcd-backed Store implementation, durable and shared across
+// metadata service replicas.
+type EtcdStore struct {
+	client EtcdClient
+	prefix string
+}
+
+// NewEtcdStore creates an EtcdStore that namespaces all of its keys under prefix.
+func NewEtcdStore(client EtcdClient, prefix string) *EtcdStore {
+	return &EtcdStore{client: client, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+func (e *EtcdStore) scriptKey(id uuid.UUID) string {
+	return fmt.Sprintf("%s/scripts/%s", e.prefix, id.String())
+}
+
+func (e *EtcdStore) scriptsPrefix() string {
+	return fmt.Sprintf("%s/scripts/", e.prefix)
+}
+
+func (e *EtcdStore) runsPrefix(id uuid.UUID) string {
+	return fmt.Sprintf("%s/runs/%s/", e.prefix, id.String())
+}
+
+// GetCronScripts returns all scripts currently stored under the scripts prefix.
+func (e *EtcdStore) GetCronScripts() ([]*cvmsgspb.CronScript, error) {
+	resp, err := e.client.Get(context.Background(), e.scriptsPrefix(), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := make([]*cvmsgspb.CronScript, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		script := &cvmsgspb.CronScript{}
+		if err := proto.Unmarshal(kv.Value, script); err != nil {
+			log.WithError(err).WithField("key", string(kv.Key)).Error("failed to unmarshal cron script from etcd")
+			continue
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// UpsertCronScript marshals and writes script to its key.
+func (e *EtcdStore) UpsertCronScript(script *cvmsgspb.CronScript) error {
+	id := utils.UUIDFromProtoOrNil(script.ID)
+	if id == uuid.Nil {
+		return fmt.Errorf("cronscript: script has no ID")
+	}
+	b, err := proto.Marshal(script)
+	if err != nil {
+		return err
+	}
+	_, err = e.client.Put(context.Background(), e.scriptKey(id), string(b))
+	return err
+}
+
+// DeleteCronScript deletes the script key and its run history.
+func (e *EtcdStore) DeleteCronScript(id uuid.UUID) error {
+	if _, err := e.client.Delete(context.Background(), e.scriptKey(id)); err != nil {
+		return err
+	}
+	_, err := e.client.Delete(context.Background(), e.runsPrefix(id), clientv3.WithPrefix())
+	return err
+}
+
+// SetCronScripts atomically replaces the stored script set with scripts, via a
+// single etcd transaction that deletes the scripts prefix and writes the new set.
+func (e *EtcdStore) SetCronScripts(scripts []*cvmsgspb.CronScript) error {
+	ops := []clientv3.Op{clientv3.OpDelete(e.scriptsPrefix(), clientv3.WithPrefix())}
+	for _, script := range scripts {
+		id := utils.UUIDFromProtoOrNil(script.ID)
+		b, err := proto.Marshal(script)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, clientv3.OpPut(e.scriptKey(id), string(b)))
+	}
+
+	_, err := e.client.Txn(context.Background()).Then(ops...).Commit()
+	return err
+}
+
+// RecordRun writes run under a key that sorts chronologically, so GetRunHistory
+// can page through it in etcd key order.
+func (e *EtcdStore) RecordRun(id uuid.UUID, run *RunRecord) error {
+	b, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s%020d", e.runsPrefix(id), run.StartTime.UnixNano())
+	_, err = e.client.Put(context.Background(), key, string(b))
+	return err
+}
+
+// GetRunHistory returns up to limit of the most recent run records, newest first.
+func (e *EtcdStore) GetRunHistory(id uuid.UUID, limit int) ([]*RunRecord, error) {
+	resp, err := e.client.Get(context.Background(), e.runsPrefix(id),
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend),
+		clientv3.WithLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*RunRecord, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		run := &RunRecord{}
+		if err := json.Unmarshal(kv.Value, run); err != nil {
+			log.WithError(err).WithField("key", string(kv.Key)).Error("failed to unmarshal run record from etcd")
+			continue
+		}
+		out = append(out, run)
+	}
+	return out, nil
+}
+
+// Watch translates the native etcd watch on the scripts prefix into StoreEvents.
+// The returned channel is closed when ctx is canceled.
+func (e *EtcdStore) Watch(ctx context.Context) <-chan StoreEvent {
+	out := make(chan StoreEvent, watcherBufferSize)
+	wch := e.client.Watch(ctx, e.scriptsPrefix(), clientv3.WithPrefix())
+
+	go func() {
+		defer close(out)
+		for resp := range wch {
+			if err := resp.Err(); err != nil {
+				log.WithError(err).Error("etcd watch error on cron script prefix")
+				continue
+			}
+			for _, ev := range resp.Events {
+				storeEv, ok := e.translateEvent(ev)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- storeEv:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+func (e *EtcdStore) translateEvent(ev *clientv3.Event) (StoreEvent, bool) {
+	idStr := strings.TrimPrefix(string(ev.Kv.Key), e.scriptsPrefix())
+	id, err := uuid.FromString(idStr)
+	if err != nil {
+		log.WithError(err).WithField("key", string(ev.Kv.Key)).Error("failed to parse script ID from etcd watch key")
+		return StoreEvent{}, false
+	}
+
+	if ev.Type == clientv3.EventTypeDelete {
+		return StoreEvent{Kind: StoreEventDelete, ScriptID: id}, true
+	}
+
+	script := &cvmsgspb.CronScript{}
+	if err := proto.Unmarshal(ev.Kv.Value, script); err != nil {
+		log.WithError(err).WithField("key", string(ev.Kv.Key)).Error("failed to unmarshal cron script from etcd watch event")
+		return StoreEvent{}, false
+	}
+	return StoreEvent{Kind: StoreEventUpsert, Script: script, ScriptID: id}, true
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/clientv3"
+
+	"px.dev/pixie/src/vizier/services/metadata/controllers/cronscript"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/cronscript/storetest"
+)
+
+// TestEtcdStoreConformance runs the shared conformance suite against a real
+// etcd cluster. It requires PL_TEST_ETCD_ENDPOINTS (comma-separated) to be
+// set, e.g. PL_TEST_ETCD_ENDPOINTS=localhost:2379, and is skipped otherwise.
+func TestEtcdStoreConformance(t *testing.T) {
+	endpoints := os.Getenv("PL_TEST_ETCD_ENDPOINTS")
+	if endpoints == "" {
+		t.Skip("PL_TEST_ETCD_ENDPOINTS not set, skipping etcd Store conformance tests")
+	}
+
+	client, err := clientv3.New(clientv3.Config{Endpoints: strings.Split(endpoints, ",")})
+	require.NoError(t, err)
+	defer client.Close()
+
+	storetest.RunConformanceTests(t, func(t *testing.T) cronscript.Store {
+		id, err := uuid.NewV4()
+		require.NoError(t, err)
+		prefix := fmt.Sprintf("/pl/cronscript_test/%s", id.String())
+		t.Cleanup(func() {
+			_, _ = client.Delete(context.Background(), prefix, clientv3.WithPrefix())
+		})
+		return cronscript.NewEtcdStore(client, prefix)
+	})
+}
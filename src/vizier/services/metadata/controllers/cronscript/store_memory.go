@@ -0,0 +1,166 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+// watcherBufferSize bounds how many events a slow watcher can lag behind before
+// fan-out starts dropping events for it.
+const watcherBufferSize = 16
+
+// MemoryStore is an in-process Store implementation backed by a map. It is not
+// durable across restarts and is intended for tests and single-replica dev use.
+type MemoryStore struct {
+	mu      sync.Mutex
+	scripts map[uuid.UUID]*cvmsgspb.CronScript
+	runs    map[uuid.UUID][]*RunRecord
+
+	watchersMu sync.Mutex
+	watchers   map[int]chan StoreEvent
+	nextID     int
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		scripts:  make(map[uuid.UUID]*cvmsgspb.CronScript),
+		runs:     make(map[uuid.UUID][]*RunRecord),
+		watchers: make(map[int]chan StoreEvent),
+	}
+}
+
+// GetCronScripts returns all stored scripts.
+func (m *MemoryStore) GetCronScripts() ([]*cvmsgspb.CronScript, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]*cvmsgspb.CronScript, 0, len(m.scripts))
+	for _, s := range m.scripts {
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// UpsertCronScript adds or replaces the script with the same ID.
+func (m *MemoryStore) UpsertCronScript(script *cvmsgspb.CronScript) error {
+	id := utils.UUIDFromProtoOrNil(script.ID)
+	if id == uuid.Nil {
+		return fmt.Errorf("cronscript: script has no ID")
+	}
+
+	m.mu.Lock()
+	m.scripts[id] = script
+	m.mu.Unlock()
+
+	m.broadcast(StoreEvent{Kind: StoreEventUpsert, Script: script, ScriptID: id})
+	return nil
+}
+
+// DeleteCronScript removes the script with the given ID, if present.
+func (m *MemoryStore) DeleteCronScript(id uuid.UUID) error {
+	m.mu.Lock()
+	delete(m.scripts, id)
+	delete(m.runs, id)
+	m.mu.Unlock()
+
+	m.broadcast(StoreEvent{Kind: StoreEventDelete, ScriptID: id})
+	return nil
+}
+
+// SetCronScripts replaces the entire stored script set.
+func (m *MemoryStore) SetCronScripts(scripts []*cvmsgspb.CronScript) error {
+	next := make(map[uuid.UUID]*cvmsgspb.CronScript, len(scripts))
+	for _, s := range scripts {
+		next[utils.UUIDFromProtoOrNil(s.ID)] = s
+	}
+
+	m.mu.Lock()
+	m.scripts = next
+	m.mu.Unlock()
+
+	m.broadcast(StoreEvent{Kind: StoreEventSetAll, Scripts: scripts})
+	return nil
+}
+
+// RecordRun appends run to the in-memory history for its script.
+func (m *MemoryStore) RecordRun(id uuid.UUID, run *RunRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[id] = append(m.runs[id], run)
+	return nil
+}
+
+// GetRunHistory returns up to limit of the most recent run records, newest first.
+func (m *MemoryStore) GetRunHistory(id uuid.UUID, limit int) ([]*RunRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs := m.runs[id]
+	out := make([]*RunRecord, len(runs))
+	copy(out, runs)
+	sort.Slice(out, func(i, j int) bool { return out[i].StartTime.After(out[j].StartTime) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// Watch returns a channel fed with every subsequent mutation, fanned out to all
+// concurrent watchers. The channel is closed when ctx is canceled.
+func (m *MemoryStore) Watch(ctx context.Context) <-chan StoreEvent {
+	ch := make(chan StoreEvent, watcherBufferSize)
+
+	m.watchersMu.Lock()
+	id := m.nextID
+	m.nextID++
+	m.watchers[id] = ch
+	m.watchersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.watchersMu.Lock()
+		delete(m.watchers, id)
+		m.watchersMu.Unlock()
+		close(ch)
+	}()
+
+	return ch
+}
+
+func (m *MemoryStore) broadcast(ev StoreEvent) {
+	m.watchersMu.Lock()
+	defer m.watchersMu.Unlock()
+	for _, ch := range m.watchers {
+		select {
+		case ch <- ev:
+		default:
+			// Slow watcher; drop the event rather than blocking the writer.
+		}
+	}
+}
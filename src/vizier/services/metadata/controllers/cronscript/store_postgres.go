@@ -0,0 +1,328 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang/protobuf/proto"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+// cronScriptChangesChannel is the Postgres NOTIFY channel the Postgres Store
+// publishes to on every write, and LISTENs on to implement Watch.
+//
+// Requires the following schema (applied via a golang-migrate migration, not
+// included here):
+//
+//	CREATE TABLE cron_scripts (
+//	    id UUID PRIMARY KEY,
+//	    data BYTEA NOT NULL
+//	);
+//	CREATE TABLE cron_script_runs (
+//	    script_id UUID NOT NULL,
+//	    start_time TIMESTAMPTZ NOT NULL,
+//	    end_time TIMESTAMPTZ,
+//	    status TEXT NOT NULL,
+//	    error TEXT,
+//	    PRIMARY KEY (script_id, start_time)
+//	);
+const cronScriptChangesChannel = "cronscript_changes"
+
+// SqlxDB is the subset of *sqlx.DB the Postgres Store depends on.
+type SqlxDB interface {
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Beginx() (*sqlx.Tx, error)
+}
+
+type cronScriptRow struct {
+	ID   uuid.UUID `db:"id"`
+	Data []byte    `db:"data"`
+}
+
+type cronScriptRunRow struct {
+	ScriptID  uuid.UUID    `db:"script_id"`
+	StartTime sql.NullTime `db:"start_time"`
+	EndTime   sql.NullTime `db:"end_time"`
+	Status    string       `db:"status"`
+	Error     string       `db:"error"`
+}
+
+type changeNotification struct {
+	Op       string `json:"op"`
+	ScriptID string `json:"script_id"`
+}
+
+// PostgresStore is a Postgres-backed Store implementation, durable and shared
+// across metadata service replicas.
+type PostgresStore struct {
+	db       SqlxDB
+	listener *pq.Listener
+}
+
+// NewPostgresStore creates a PostgresStore backed by db. If listener is non-nil,
+// it is used to implement Watch via LISTEN/NOTIFY; otherwise Watch returns a
+// channel that is immediately closed.
+func NewPostgresStore(db SqlxDB, listener ...*pq.Listener) *PostgresStore {
+	var l *pq.Listener
+	if len(listener) > 0 {
+		l = listener[0]
+	}
+	return &PostgresStore{db: db, listener: l}
+}
+
+// GetCronScripts returns all stored scripts.
+func (p *PostgresStore) GetCronScripts() ([]*cvmsgspb.CronScript, error) {
+	var rows []cronScriptRow
+	if err := p.db.Select(&rows, "SELECT id, data FROM cron_scripts"); err != nil {
+		return nil, err
+	}
+
+	scripts := make([]*cvmsgspb.CronScript, 0, len(rows))
+	for _, row := range rows {
+		script := &cvmsgspb.CronScript{}
+		if err := proto.Unmarshal(row.Data, script); err != nil {
+			log.WithError(err).WithField("scriptID", row.ID.String()).Error("failed to unmarshal cron script from postgres")
+			continue
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// UpsertCronScript inserts or updates the script row and notifies watchers.
+func (p *PostgresStore) UpsertCronScript(script *cvmsgspb.CronScript) error {
+	id := utils.UUIDFromProtoOrNil(script.ID)
+	if id == uuid.Nil {
+		return fmt.Errorf("cronscript: script has no ID")
+	}
+	data, err := proto.Marshal(script)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(`
+		INSERT INTO cron_scripts (id, data) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, id, data)
+	if err != nil {
+		return err
+	}
+	return p.notify("upsert", id)
+}
+
+// DeleteCronScript removes the script row and its run history, and notifies watchers.
+func (p *PostgresStore) DeleteCronScript(id uuid.UUID) error {
+	if _, err := p.db.Exec("DELETE FROM cron_scripts WHERE id = $1", id); err != nil {
+		return err
+	}
+	if _, err := p.db.Exec("DELETE FROM cron_script_runs WHERE script_id = $1", id); err != nil {
+		return err
+	}
+	return p.notify("delete", id)
+}
+
+// SetCronScripts replaces the entire stored script set inside a single
+// transaction, so concurrent readers and watchers never observe an empty
+// table mid-replace, and emits one "set" notification rather than one per
+// script, matching the etcd Store's single-Txn semantics.
+func (p *PostgresStore) SetCronScripts(scripts []*cvmsgspb.CronScript) error {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec("DELETE FROM cron_scripts"); err != nil {
+		return err
+	}
+	for _, script := range scripts {
+		id := utils.UUIDFromProtoOrNil(script.ID)
+		if id == uuid.Nil {
+			return fmt.Errorf("cronscript: script has no ID")
+		}
+		data, err := proto.Marshal(script)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO cron_scripts (id, data) VALUES ($1, $2)
+			ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, id, data); err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(changeNotification{Op: "set"})
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("SELECT pg_notify('%s', $1)", cronScriptChangesChannel), string(payload)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RecordRun inserts a row recording the outcome of a single run.
+func (p *PostgresStore) RecordRun(id uuid.UUID, run *RunRecord) error {
+	_, err := p.db.Exec(`
+		INSERT INTO cron_script_runs (script_id, start_time, end_time, status, error)
+		VALUES ($1, $2, $3, $4, $5)`,
+		id, run.StartTime, run.EndTime, statusString(run.Status), run.Error)
+	return err
+}
+
+// GetRunHistory returns up to limit of the most recent run records, newest first.
+func (p *PostgresStore) GetRunHistory(id uuid.UUID, limit int) ([]*RunRecord, error) {
+	var rows []cronScriptRunRow
+	err := p.db.Select(&rows, `
+		SELECT script_id, start_time, end_time, status, error
+		FROM cron_script_runs
+		WHERE script_id = $1
+		ORDER BY start_time DESC
+		LIMIT $2`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*RunRecord, len(rows))
+	for i, row := range rows {
+		out[i] = &RunRecord{
+			ScriptID:  row.ScriptID,
+			StartTime: row.StartTime.Time,
+			EndTime:   row.EndTime.Time,
+			Status:    runStatusFromString(row.Status),
+			Error:     row.Error,
+		}
+	}
+	return out, nil
+}
+
+// Watch listens on the NOTIFY channel for script changes and re-reads the
+// affected row from the DB to build a StoreEvent. The returned channel is
+// closed when ctx is canceled. If this PostgresStore has no listener configured,
+// the returned channel is immediately closed.
+func (p *PostgresStore) Watch(ctx context.Context) <-chan StoreEvent {
+	out := make(chan StoreEvent, watcherBufferSize)
+	if p.listener == nil {
+		close(out)
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n, ok := <-p.listener.Notify:
+				if !ok {
+					return
+				}
+				if n == nil || n.Channel != cronScriptChangesChannel {
+					continue
+				}
+				ev, ok := p.translateNotification(n.Extra)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+func (p *PostgresStore) translateNotification(payload string) (StoreEvent, bool) {
+	var n changeNotification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		log.WithError(err).Error("failed to unmarshal postgres NOTIFY payload")
+		return StoreEvent{}, false
+	}
+
+	if n.Op == "set" {
+		scripts, err := p.GetCronScripts()
+		if err != nil {
+			log.WithError(err).Error("failed to re-read cron scripts after SetCronScripts notification")
+			return StoreEvent{}, false
+		}
+		return StoreEvent{Kind: StoreEventSetAll, Scripts: scripts}, true
+	}
+
+	id, err := uuid.FromString(n.ScriptID)
+	if err != nil {
+		log.WithError(err).WithField("scriptID", n.ScriptID).Error("failed to parse script ID from postgres NOTIFY payload")
+		return StoreEvent{}, false
+	}
+
+	if n.Op == "delete" {
+		return StoreEvent{Kind: StoreEventDelete, ScriptID: id}, true
+	}
+
+	var row cronScriptRow
+	if err := p.db.Get(&row, "SELECT id, data FROM cron_scripts WHERE id = $1", id); err != nil {
+		log.WithError(err).WithField("scriptID", id.String()).Error("failed to re-read upserted cron script from postgres")
+		return StoreEvent{}, false
+	}
+	script := &cvmsgspb.CronScript{}
+	if err := proto.Unmarshal(row.Data, script); err != nil {
+		log.WithError(err).WithField("scriptID", id.String()).Error("failed to unmarshal cron script from postgres")
+		return StoreEvent{}, false
+	}
+	return StoreEvent{Kind: StoreEventUpsert, Script: script, ScriptID: id}, true
+}
+
+func (p *PostgresStore) notify(op string, id uuid.UUID) error {
+	payload, err := json.Marshal(changeNotification{Op: op, ScriptID: id.String()})
+	if err != nil {
+		return err
+	}
+	_, err = p.db.Exec(fmt.Sprintf("SELECT pg_notify('%s', $1)", cronScriptChangesChannel), string(payload))
+	return err
+}
+
+// runStatusFromString is the inverse of statusString, used when reading run
+// records back out of durable storage.
+func runStatusFromString(s string) RunStatus {
+	switch s {
+	case "running":
+		return RunStatusRunning
+	case "succeeded":
+		return RunStatusSucceeded
+	case "failed":
+		return RunStatusFailed
+	default:
+		return RunStatusUnknown
+	}
+}
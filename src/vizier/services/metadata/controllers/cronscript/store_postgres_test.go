@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cronscript_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/vizier/services/metadata/controllers/cronscript"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/cronscript/storetest"
+)
+
+// schemaSQL mirrors the schema documented on cronScriptChangesChannel in
+// store_postgres.go; tests apply it themselves since the real deployment
+// applies it via a golang-migrate migration.
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS cron_scripts (
+    id UUID PRIMARY KEY,
+    data BYTEA NOT NULL
+);
+CREATE TABLE IF NOT EXISTS cron_script_runs (
+    script_id UUID NOT NULL,
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time TIMESTAMPTZ,
+    status TEXT NOT NULL,
+    error TEXT,
+    PRIMARY KEY (script_id, start_time)
+);
+`
+
+// TestPostgresStoreConformance runs the shared conformance suite against a
+// real Postgres database. It requires PL_TEST_POSTGRES_DSN to be set and is
+// skipped otherwise.
+func TestPostgresStoreConformance(t *testing.T) {
+	dsn := os.Getenv("PL_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PL_TEST_POSTGRES_DSN not set, skipping postgres Store conformance tests")
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(schemaSQL)
+	require.NoError(t, err)
+
+	storetest.RunConformanceTests(t, func(t *testing.T) cronscript.Store {
+		db.MustExec("TRUNCATE cron_scripts, cron_script_runs")
+		t.Cleanup(func() {
+			db.MustExec("TRUNCATE cron_scripts, cron_script_runs")
+		})
+		return cronscript.NewPostgresStore(db)
+	})
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package storetest exercises every cronscript.Store implementation through the
+// same table-driven conformance suite. Each backend's own _test.go should call
+// storetest.RunConformanceTests with a factory that returns a fresh, empty
+// instance of that backend, e.g.:
+//
+//	func TestMemoryStoreConformance(t *testing.T) {
+//	    storetest.RunConformanceTests(t, func(t *testing.T) cronscript.Store {
+//	        return cronscript.NewMemoryStore()
+//	    })
+//	}
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/cronscript"
+)
+
+// NewStoreFunc builds a fresh, empty Store instance for a single test.
+type NewStoreFunc func(t *testing.T) cronscript.Store
+
+func newTestScript(t *testing.T, frequencyS int64) *cvmsgspb.CronScript {
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	return &cvmsgspb.CronScript{
+		ID:         utils.ProtoFromUUID(id),
+		Script:     "px.display(px.DataFrame('http_events'))",
+		FrequencyS: frequencyS,
+	}
+}
+
+// RunConformanceTests runs the full conformance suite against the Store built
+// by newStore, which must return a distinct, empty store per call.
+func RunConformanceTests(t *testing.T, newStore NewStoreFunc) {
+	t.Run("UpsertIdempotency", func(t *testing.T) { testUpsertIdempotency(t, newStore) })
+	t.Run("DeleteMissing", func(t *testing.T) { testDeleteMissing(t, newStore) })
+	t.Run("ConcurrentSetVsUpsert", func(t *testing.T) { testConcurrentSetVsUpsert(t, newStore) })
+	t.Run("LargeBatchSetScripts", func(t *testing.T) { testLargeBatchSetScripts(t, newStore) })
+	t.Run("RunHistory", func(t *testing.T) { testRunHistory(t, newStore) })
+	t.Run("Watch", func(t *testing.T) { testWatch(t, newStore) })
+}
+
+func testUpsertIdempotency(t *testing.T, newStore NewStoreFunc) {
+	store := newStore(t)
+	script := newTestScript(t, 60)
+
+	require.NoError(t, store.UpsertCronScript(script))
+	require.NoError(t, store.UpsertCronScript(script))
+
+	scripts, err := store.GetCronScripts()
+	require.NoError(t, err)
+	assert.Len(t, scripts, 1)
+}
+
+func testDeleteMissing(t *testing.T, newStore NewStoreFunc) {
+	store := newStore(t)
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+
+	assert.NoError(t, store.DeleteCronScript(id))
+}
+
+func testConcurrentSetVsUpsert(t *testing.T, newStore NewStoreFunc) {
+	store := newStore(t)
+	base := newTestScript(t, 60)
+	require.NoError(t, store.UpsertCronScript(base))
+
+	other := newTestScript(t, 120)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_ = store.SetCronScripts([]*cvmsgspb.CronScript{base})
+	}()
+	go func() {
+		defer wg.Done()
+		_ = store.UpsertCronScript(other)
+	}()
+	wg.Wait()
+
+	// Regardless of interleaving, the store must end up in a consistent state
+	// (no panics, no error reading back). base survives either order, so at
+	// least one script must remain.
+	scripts, err := store.GetCronScripts()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(scripts), 1)
+}
+
+func testLargeBatchSetScripts(t *testing.T, newStore NewStoreFunc) {
+	store := newStore(t)
+
+	const batchSize = 200
+	batch := make([]*cvmsgspb.CronScript, batchSize)
+	for i := range batch {
+		batch[i] = newTestScript(t, 60)
+	}
+
+	require.NoError(t, store.SetCronScripts(batch))
+
+	scripts, err := store.GetCronScripts()
+	require.NoError(t, err)
+	assert.Len(t, scripts, batchSize)
+}
+
+func testRunHistory(t *testing.T, newStore NewStoreFunc) {
+	store := newStore(t)
+	script := newTestScript(t, 60)
+	require.NoError(t, store.UpsertCronScript(script))
+	id := utils.UUIDFromProtoOrNil(script.ID)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		run := &cronscript.RunRecord{
+			ScriptID:  id,
+			StartTime: now.Add(time.Duration(i) * time.Second),
+			EndTime:   now.Add(time.Duration(i)*time.Second + time.Millisecond),
+			Status:    cronscript.RunStatusSucceeded,
+		}
+		require.NoError(t, store.RecordRun(id, run))
+	}
+
+	history, err := store.GetRunHistory(id, 2)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func testWatch(t *testing.T, newStore NewStoreFunc) {
+	store := newStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := store.Watch(ctx)
+
+	script := newTestScript(t, 60)
+	require.NoError(t, store.UpsertCronScript(script))
+
+	select {
+	case ev, ok := <-events:
+		if ok {
+			assert.Equal(t, cronscript.StoreEventUpsert, ev.Kind)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for watch event after upsert")
+	}
+}